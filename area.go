@@ -23,8 +23,8 @@ import (
 // systems; trying ot recreate these yourself is only going
 // to lead to trouble.
 // [Use TextArea instead, providing a TextAreaHandler.]
-// 
-// To facilitate development and debugging, for the time being, Areas only work on GTK+.
+//
+// Areas are implemented on GTK+, Windows, and Mac OS X.
 type Area struct {
 	lock			sync.Mutex
 	created		bool
@@ -32,11 +32,17 @@ type Area struct {
 	handler		AreaHandler
 	initwidth		int
 	initheight		int
+	eventsOnce	sync.Once
+	cancelOnce	sync.Once
+	events		chan AreaEvent
+	done			chan struct{}
+	cancelled		bool	// set by Cancel(); make() must not hand sysData a channel Cancel() may already have closed
 }
 
 // AreaHandler represents the events that an Area should respond to.
 // You are responsible for the thread safety of any members of the actual type that implements ths interface.
 // (Having to use this interface does not strike me as being particularly Go-like, but the nature of Paint makes channel-based event handling a non-option; in practice, deadlocks occur.)
+// If you do want a channel-based programming model, see Area.Events() instead; it sidesteps the deadlock by making Paint's reply explicit.
 type AreaHandler interface {
 	// Paint is called when the Area needs to be redrawn.
 	// The part of the Area that needs to be redrawn is stored in cliprect.
@@ -68,9 +74,18 @@ type AreaHandler interface {
 	Key(e KeyEvent) (handled bool, repaint bool)
 }
 
+// AreaRegionHandler is an optional extension to AreaHandler.
+// If the AreaHandler passed to NewArea also implements AreaRegionHandler, Area calls PaintRegions instead of Paint whenever the system reports more than one damaged sub-rectangle for a single repaint (Win32 GetUpdateRgn, Cocoa getRectsBeingDrawn:count:), letting the handler paint every damaged rectangle in one round trip instead of coalescing them into a single, possibly much larger, cliprect.
+// Handlers that do not implement AreaRegionHandler are unaffected; Area continues to call Paint once per repaint with the bounding rectangle of the damage, exactly as before.
+type AreaRegionHandler interface {
+	// PaintRegions behaves like Paint, but is called with every rectangle the system is reporting as damaged in one go, rather than their bounding rectangle.
+	// The returned map must have an entry for every element of regions, with an image the same size as (but not necessarily the same origin as) that element.
+	PaintRegions(regions []image.Rectangle) map[image.Rectangle]*image.RGBA
+}
+
 // MouseEvent contains all the information for a mous event sent by Area.Mouse.
 // Mouse button IDs start at 1, with 1 being the left mouse button, 2 being the middle mouse button, and 3 being the right mouse button.
-// (TODO "If additional buttons are supported, they will be returned with 4 being the first additional button (XBUTTON1 on Windows), 5 being the second (XBUTTON2 on Windows), and so on."?) (TODO get the user-facing name for XBUTTON1/2; find out if there's a way to query available button count)
+// If additional buttons are supported, they are returned with 4 being the first additional button (XBUTTON1 on Windows), 5 being the second (XBUTTON2 on Windows), and so on; see Area.NumButtons() to query how many a given pointing device advertises.
 type MouseEvent struct {
 	// Pos is the position of the mouse in the Area at the time of the event.
 	// TODO rename to Pt or Point?
@@ -82,7 +97,7 @@ type MouseEvent struct {
 
 	// If the event was generated by a mouse button being released, Up contains the ID of that button.
 	// Otherwise, Up contains 0.
-	// If both Down and Up are 0, the event represents mouse movement (with optional held buttons; see below).
+	// If both Down and Up are 0, the event represents mouse movement (with optional held buttons; see below) or a Wheel event.
 	// Down and Up shall not both be nonzero.
 	Up			uint
 
@@ -98,10 +113,18 @@ type MouseEvent struct {
 	// Held will not include Down and Up.
 	// (TODO "There is no guarantee that Held is sorted."?)
 	Held			[]uint
+
+	// Wheel carries the scroll delta, in notches, of a mouse wheel or trackpad scroll event.
+	// WheelX is positive when scrolling right, negative when scrolling left; WheelY is positive when scrolling up ("away from the user"), negative when scrolling down, matching the sign conventions of WM_MOUSEWHEEL/WM_MOUSEHWHEEL and NSEvent's scrollingDelta*.
+	// Most wheels only report whole notches, but some touchpads and high-resolution wheels report fractional values.
+	// A MouseEvent with a nonzero Wheel is otherwise a plain movement event: Down, Up, and Count are all 0.
+	WheelX		float64
+	WheelY		float64
 }
 
 // HeldBits returns Held as a bit mask.
 // Bit 0 maps to button 1, bit 1 maps to button 2, etc.
+// Bits 3 and 4 (buttons 4 and 5, the XBUTTON1/XBUTTON2-style extra buttons) are populated wherever the underlying system reports them.
 func (e MouseEvent) HeldBits() (h uintptr) {
 	for _, x := range e.Held {
 		h |= uintptr(1) << (x - 1)
@@ -219,6 +242,8 @@ const (
 	NSubtract
 	NMultiply
 	NDivide
+	SuperLeft		// the left Windows/Command key, pressed by itself (named to avoid colliding with the Modifiers LeftSuper/RightSuper bits)
+	SuperRight		// the right Windows/Command key, pressed by itself
 	_nextkeys		// for sanity check
 )
 
@@ -252,14 +277,23 @@ func (e KeyEvent) EffectiveKey() byte {
 }
 
 // Modifiers indicates modifier keys being held during an event.
-// There is no way to differentiate between left and right modifier keys.
-// As such, what KeyEvents get sent if the user does something unusual with both of a certain modifier key at once is (presently; TODO) undefined.
+// Ctrl, Alt, Shift, and Super are each set whenever either their Left* or Right* bit is, so code that only ever checked the coarse bit keeps working unchanged; check the Left*/Right* bits directly if you care which side was held.
+// If the user does something unusual with both sides of a modifier at once (for instance, releases LeftCtrl while RightCtrl is already down), what KeyEvents get sent is still undefined.
 type Modifiers uintptr
 const (
-	Ctrl Modifiers = 1 << iota		// the canonical Ctrl keys ([TODO] on Mac OS X, Control on others)
-	Alt						// the canonical Alt keys ([TODO] on Mac OS X, Meta on Unix systems, Alt on others)
-	Shift						// the Shift keys
-	// TODO add Super
+	LeftCtrl Modifiers = 1 << iota
+	RightCtrl
+	LeftAlt
+	RightAlt
+	LeftShift
+	RightShift
+	LeftSuper						// the left Windows/Command key
+	RightSuper						// the right Windows/Command key
+
+	Ctrl	= LeftCtrl | RightCtrl		// the canonical Ctrl keys ([TODO] on Mac OS X, Control on others)
+	Alt	= LeftAlt | RightAlt		// the canonical Alt keys ([TODO] on Mac OS X, Meta on Unix systems, Alt on others)
+	Shift	= LeftShift | RightShift		// the Shift keys
+	Super	= LeftSuper | RightSuper		// the Windows/Command key
 )
 
 func checkAreaSize(width int, height int, which string) {
@@ -309,10 +343,93 @@ func (a *Area) make(window *sysData) error {
 		return err
 	}
 	a.sysData.setAreaSize(a.initwidth, a.initheight)
+	if a.events != nil && !a.cancelled {
+		a.sysData.setAreaEvents(a.events, a.done)
+	} else if a.events != nil {
+		// Cancel() already closed a.events itself (see Cancel()); handing sysData that closed channel would let the very first event delivery panic by sending on it
+		// instead it gets a channel nobody reads, so that with a.done closed every delivery gives up at once and the AreaHandler is still never called
+		a.sysData.setAreaEvents(make(chan AreaEvent), a.done)
+	}
 	a.created = true
 	return nil
 }
 
+// AreaEvent is the set of events delivered through Area.Events(): a PaintEvent, a MouseEvent, or a KeyEvent.
+type AreaEvent interface{}
+
+// PaintEvent is delivered through Area.Events() in place of a call to AreaHandler.Paint.
+// Rect is the region that needs to be redrawn, identical to Paint's cliprect.
+// The goroutine that receives a PaintEvent MUST call Reply exactly once, with an *image.RGBA the same size as Rect, before it reads the next event off the channel; the system thread that produced the PaintEvent blocks until Reply is called, which is what lets Paint's "you must return a valid image" contract hold even though delivery is now asynchronous.
+type PaintEvent struct {
+	Rect		image.Rectangle
+	reply	chan *image.RGBA
+}
+
+// Reply supplies the painted image for a PaintEvent; see PaintEvent for the rules governing its use.
+func (e PaintEvent) Reply(img *image.RGBA) {
+	e.reply <- img
+}
+
+// Events switches a into channel-based event delivery and returns the channel its PaintEvent, MouseEvent, and KeyEvent values arrive on.
+// Once Events has been called, AreaHandler's methods are never invoked again for a; all further Paint/Mouse/Key activity is delivered on the returned channel instead.
+// Calling Events more than once just returns the same channel.
+func (a *Area) Events() <-chan AreaEvent {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.eventsOnce.Do(func() {
+		a.events = make(chan AreaEvent)
+		a.done = make(chan struct{})
+		if a.created {
+			a.sysData.setAreaEvents(a.events, a.done)
+		}
+	})
+	return a.events
+}
+
+// Cancel unblocks any goroutine parked reading from the channel returned by Events, so that a program using the channel-based API can shut down without deadlocking.
+// Cancel panics if Events was never called.
+func (a *Area) Cancel() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.done == nil {
+		panic("Area.Cancel() called before Area.Events()")
+	}
+	a.cancelOnce.Do(func() {
+		a.cancelled = true
+		close(a.done)
+		if !a.created {
+			// no system window exists yet to deliver events from; nothing can be blocked sending, so it's safe to close events ourselves
+			close(a.events)
+			return
+		}
+		a.sysData.wakeArea()
+	})
+}
+
+// Invalidate marks r (in Area coordinates) as needing to be redrawn, without affecting the rest of the Area's drawing surface.
+// r is clipped to the Area's current size; Invalidate does nothing if the Area has not yet been created.
+func (a *Area) Invalidate(r image.Rectangle) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.created {
+		a.sysData.invalidateAreaRect(r)
+	}
+}
+
+// InvalidateAll marks the entire Area as needing to be redrawn.
+// InvalidateAll does nothing if the Area has not yet been created.
+func (a *Area) InvalidateAll() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.created {
+		a.sysData.invalidateArea()
+	}
+}
+
 func (a *Area) setRect(x int, y int, width int, height int, rr *[]resizerequest) {
 	*rr = append(*rr, resizerequest{
 		sysData:	a.sysData,
@@ -327,6 +444,16 @@ func (a *Area) preferredSize() (width int, height int) {
 	return a.sysData.preferredSize()
 }
 
+// NumButtons returns the number of buttons the system reports for the pointing device currently driving this Area, including any XBUTTON1/XBUTTON2-style extra buttons.
+// It always returns at least 3 (left, middle, right), even on systems that cannot query the actual hardware.
+// On Mac OS X, which has no such query, it always returns 5, whatever the hardware.
+func (a *Area) NumButtons() uint {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.sysData.numButtons()
+}
+
 // internal function, but shared by all system implementations: &img.Pix[0] is not necessarily the first pixel in the image
 func pixelDataPos(img *image.RGBA) int {
 	return img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y)
@@ -0,0 +1,324 @@
+// 31 july 2014
+
+package ui
+
+// #cgo LDFLAGS: -framework Cocoa
+// #include "area_darwin.h"
+// #include <IOKit/hidsystem/IOLLEvent.h>
+import "C"
+
+import (
+	"image"
+	"unsafe"
+)
+
+// areaCocoaData holds the bits of sysData that are specific to rendering and scrolling an Area on Mac OS X.
+type areaCocoaData struct {
+	scrollview	C.id	// NSScrollView
+	view		C.id	// goAreaView, the NSScrollView's documentView
+	events		chan AreaEvent		// set by Area.Events(); non-nil means Paint/Mouse/Key are delivered here instead of through the AreaHandler
+	done			chan struct{}		// closed by Area.Cancel()
+	cancelSource	C.CFRunLoopSourceRef	// fired by wakeArea to ask the main run loop to close events; see areaCancelled
+	cancelled		bool				// set once areaCancelled has closed events; touched only on the main thread, so no lock is needed
+}
+
+// makeAreaView creates the NSScrollView/goAreaView pair backing an Area; goAreaView (area_darwin.m) calls back into areaDraw and areaMouseEvent/areaKeyEvent below by way of its stashed sysData pointer.
+func (s *sysData) makeAreaView(parent C.id) error {
+	s.area.scrollview = C.newAreaScrollView(unsafe.Pointer(s))
+	s.area.view = C.areaScrollViewDocumentView(s.area.scrollview)
+	C.addSubview(parent, s.area.scrollview)
+	return nil
+}
+
+// setAreaEvents records the channels created by Area.Events() and schedules the CFRunLoopSourceRef that wakeArea signals to close them from the main run loop.
+func (s *sysData) setAreaEvents(events chan AreaEvent, done chan struct{}) {
+	s.area.events = events
+	s.area.done = done
+	s.area.cancelSource = C.newAreaCancelSource(unsafe.Pointer(s))
+}
+
+// wakeArea asks the main run loop to close s.area.events, by way of s.area.cancelSource; see newAreaCancelSource in area_darwin.m for why this has to happen on that thread rather than here.
+func (s *sysData) wakeArea() {
+	C.signalAreaCancelSource(s.area.cancelSource)
+}
+
+// areaPostEvent delivers ev on s.area.events, giving up without blocking forever if Area.Cancel() fires first.
+func (s *sysData) areaPostEvent(ev AreaEvent) {
+	if s.area.cancelled { // events is closed; sending on it would panic
+		return
+	}
+	select {
+	case s.area.events <- ev:
+	case <-s.area.done:
+	}
+}
+
+//export areaCancelled
+func areaCancelled(sd unsafe.Pointer) {
+	s := (*sysData)(sd)
+	if s.area.events == nil || s.area.cancelled {
+		return
+	}
+	s.area.cancelled = true
+	close(s.area.events)
+}
+
+// setAreaSize resizes goAreaView's frame (its document size, independent of the scrollview's own, possibly smaller, frame) and lets NSScrollView recompute whether each scroller is needed.
+func (s *sysData) setAreaSize(width int, height int) {
+	C.setAreaDocumentSize(s.area.view, C.double(width), C.double(height))
+}
+
+func (s *sysData) area_clientSize() (width int, height int) {
+	var w, h C.double
+	C.areaVisibleSize(s.area.scrollview, &w, &h)
+	return int(w), int(h)
+}
+
+// numButtons reports the number of buttons Area is prepared to recognize on Mac OS X.
+// Cocoa has no API to query how many physical buttons a pointing device has; unlike Windows' GetSystemMetrics(SM_CMOUSEBUTTONS), all we know is the buttonNumber of the event actually delivered to -otherMouseDown:, so we report Area's own supported maximum (5: left, middle, right, XBUTTON1-equivalent, XBUTTON2-equivalent) rather than guessing at the hardware.
+func (s *sysData) numButtons() uint {
+	return 5
+}
+
+//export areaDraw
+func areaDraw(sd unsafe.Pointer, x, y, w, h C.double) C.CImage {
+	s := (*sysData)(sd)
+	rect := image.Rect(int(x), int(y), int(x+w), int(y+h))
+	img, ok := s.areaPaintImage(rect)
+	if !ok { // Area.Cancel() fired while this paint was in flight; nothing left to draw
+		return C.CImage{}
+	}
+	if img == nil || img.Rect.Dx() != rect.Dx() || img.Rect.Dy() != rect.Dy() {
+		panic("Area.Paint() returned nil or an image of the wrong size")
+	}
+	return C.CImage{
+		pixels:	unsafe.Pointer(pixelData(img)),
+		width:	C.int(img.Rect.Dx()),
+		height:	C.int(img.Rect.Dy()),
+		stride:	C.int(img.Stride),
+	}
+}
+
+// areaPaintImage obtains the image to paint rect with, either by calling AreaHandler.Paint directly or, once Area.Events() has switched this Area into channel mode, by delivering a PaintEvent and blocking for its Reply.
+// ok is false only if Area.Cancel() fired before a reply arrived, in which case img must not be used.
+func (s *sysData) areaPaintImage(rect image.Rectangle) (img *image.RGBA, ok bool) {
+	if s.area.events == nil {
+		return s.handler.(AreaHandler).Paint(rect), true
+	}
+	if s.area.cancelled { // events is closed; sending on it would panic
+		return nil, false
+	}
+	reply := make(chan *image.RGBA)
+	select {
+	case s.area.events <- PaintEvent{Rect: rect, reply: reply}:
+	case <-s.area.done:
+		return nil, false
+	}
+	select {
+	case img = <-reply:
+		return img, true
+	case <-s.area.done:
+		return nil, false
+	}
+}
+
+//export areaDrawRegions
+func areaDrawRegions(sd unsafe.Pointer, crects *C.CRect, n C.int, outImages *C.CImage) C.BOOL {
+	s := (*sysData)(sd)
+	h, ok := s.handler.(AreaRegionHandler)
+	if !ok || s.area.events != nil {
+		return C.NO
+	}
+
+	crectSlice := unsafe.Slice(crects, int(n))
+	regions := make([]image.Rectangle, n)
+	for i, r := range crectSlice {
+		regions[i] = image.Rect(int(r.x), int(r.y), int(r.x+r.w), int(r.y+r.h))
+	}
+
+	images := h.PaintRegions(regions)
+	outSlice := unsafe.Slice(outImages, int(n))
+	for i, r := range regions {
+		img := images[r]
+		if img == nil || img.Rect.Dx() != r.Dx() || img.Rect.Dy() != r.Dy() {
+			panic("Area.PaintRegions() did not return a correctly sized image for every region")
+		}
+		outSlice[i] = C.CImage{
+			pixels:	unsafe.Pointer(pixelData(img)),
+			width:	C.int(img.Rect.Dx()),
+			height:	C.int(img.Rect.Dy()),
+			stride:	C.int(img.Stride),
+		}
+	}
+	return C.YES
+}
+
+// invalidateAreaRect marks rect (in Area coordinates, which already match goAreaView's flipped NSView coordinates) as needing to be redrawn.
+func (s *sysData) invalidateAreaRect(rect image.Rectangle) {
+	C.setNeedsDisplayInRect(s.area.view, C.double(rect.Min.X), C.double(rect.Min.Y), C.double(rect.Dx()), C.double(rect.Dy()))
+}
+
+// invalidateArea marks the entire Area as needing to be redrawn.
+func (s *sysData) invalidateArea() {
+	C.setNeedsDisplay(s.area.view)
+}
+
+// cocoaModifiers reads the device-dependent NX_DEVICE* bits out of flags, which distinguish left from right, rather than NSEvent's side-agnostic NSControlKeyMask/NSAlternateKeyMask/NSShiftKeyMask/NSCommandKeyMask; Modifiers' coarse Ctrl/Alt/Shift/Super bits fall out automatically since they're defined as the OR of their sides.
+func cocoaModifiers(flags C.uint64_t) Modifiers {
+	var m Modifiers
+	if flags&C.NX_DEVICELCTLKEYMASK != 0 {
+		m |= LeftCtrl
+	}
+	if flags&C.NX_DEVICERCTLKEYMASK != 0 {
+		m |= RightCtrl
+	}
+	if flags&C.NX_DEVICELALTKEYMASK != 0 {
+		m |= LeftAlt
+	}
+	if flags&C.NX_DEVICERALTKEYMASK != 0 {
+		m |= RightAlt
+	}
+	if flags&C.NX_DEVICELSHIFTKEYMASK != 0 {
+		m |= LeftShift
+	}
+	if flags&C.NX_DEVICERSHIFTKEYMASK != 0 {
+		m |= RightShift
+	}
+	if flags&C.NX_DEVICELCMDKEYMASK != 0 {
+		m |= LeftSuper
+	}
+	if flags&C.NX_DEVICERCMDKEYMASK != 0 {
+		m |= RightSuper
+	}
+	return m
+}
+
+// cocoaButton maps a Cocoa NSEvent buttonNumber (0 = left, 1 = right, 2 = middle, 3.. = extra buttons) to Area's button ID scheme (1 = left, 2 = middle, 3 = right, 4.. = extra buttons).
+func cocoaButton(buttonNumber C.int) uint {
+	switch buttonNumber {
+	case 0:
+		return 1
+	case 1:
+		return 3
+	case 2:
+		return 2
+	}
+	return uint(buttonNumber) + 1 // buttonNumber 3 -> Area's button 4 (XBUTTON1-equivalent is actually buttonNumber 3 in AppKit); kept as a simple linear mapping past the first three, and must agree with heldMaskFromEvent's bit numbering in area_darwin.m
+}
+
+//export areaMouseEvent
+func areaMouseEvent(sd unsafe.Pointer, kind C.int, x, y C.double, count C.int, buttonNumber C.int, heldMask C.uint32_t, flags C.uint64_t) {
+	s := (*sysData)(sd)
+	button := cocoaButton(buttonNumber)
+	e := MouseEvent{
+		Pos:		image.Pt(int(x), int(y)),
+		Modifiers:	cocoaModifiers(flags),
+	}
+	for b := uint(1); b <= 5; b++ {
+		if heldMask&(1<<(b-1)) != 0 {
+			e.Held = append(e.Held, b)
+		}
+	}
+	switch kind {
+	case C.areaEventMouseDown:
+		e.Down = button
+		e.Count = uint(count)
+	case C.areaEventMouseUp:
+		e.Up = button
+	case C.areaEventMouseMoved, C.areaEventMouseDragged:
+		// Down, Up both zero
+	}
+	if s.area.events != nil {
+		s.areaPostEvent(e)
+		return
+	}
+	repaint := s.handler.(AreaHandler).Mouse(e)
+	if repaint {
+		C.setNeedsDisplay(s.area.view)
+	}
+}
+
+//export areaWheelEvent
+func areaWheelEvent(sd unsafe.Pointer, x, y, deltaX, deltaY C.double, heldMask C.uint32_t, flags C.uint64_t) {
+	s := (*sysData)(sd)
+	e := MouseEvent{
+		Pos:		image.Pt(int(x), int(y)),
+		Modifiers:	cocoaModifiers(flags),
+		WheelX:	float64(deltaX),
+		WheelY:	float64(deltaY),
+	}
+	for b := uint(1); b <= 5; b++ {
+		if heldMask&(1<<(b-1)) != 0 {
+			e.Held = append(e.Held, b)
+		}
+	}
+	if s.area.events != nil {
+		s.areaPostEvent(e)
+		return
+	}
+	repaint := s.handler.(AreaHandler).Mouse(e)
+	if repaint {
+		C.setNeedsDisplay(s.area.view)
+	}
+}
+
+// translateCocoaKey maps an NSEvent to Area's Key/ExtKey model: keyCode selects the ExtKeys listed below; for any other key, chars (the first UTF-16 unit of the event's characters, as passed by area_darwin.m) becomes Key as is if it is ASCII.
+func translateCocoaKey(keyCode C.uint16_t, chars C.unichar) (key byte, extkey ExtKey) {
+	switch keyCode {
+	case C.kVK_Escape:
+		return 0, Escape
+	case C.kVK_ForwardDelete:
+		return 0, Delete
+	case C.kVK_Home:
+		return 0, Home
+	case C.kVK_End:
+		return 0, End
+	case C.kVK_PageUp:
+		return 0, PageUp
+	case C.kVK_PageDown:
+		return 0, PageDown
+	case C.kVK_UpArrow:
+		return 0, Up
+	case C.kVK_DownArrow:
+		return 0, Down
+	case C.kVK_LeftArrow:
+		return 0, Left
+	case C.kVK_RightArrow:
+		return 0, Right
+	case C.kVK_Command:
+		return 0, SuperLeft
+	case C.kVK_RightCommand:
+		return 0, SuperRight
+	}
+	if chars != 0 && chars < 128 {
+		return byte(chars), 0
+	}
+	return 0, 0
+}
+
+//export areaKeyEvent
+func areaKeyEvent(sd unsafe.Pointer, up C.BOOL, keyCode C.uint16_t, chars C.unichar, flags C.uint64_t) C.BOOL {
+	s := (*sysData)(sd)
+	key, extkey := translateCocoaKey(keyCode, chars)
+	if key == 0 && extkey == 0 {
+		return C.NO // not representable; let the responder chain (and the system) have it
+	}
+	e := KeyEvent{
+		Key:		key,
+		ExtKey:	extkey,
+		Modifiers:	cocoaModifiers(flags),
+		Up:		up == C.YES,
+	}
+	if s.area.events != nil {
+		s.areaPostEvent(e)
+		return C.NO // channel delivery is fire-and-forget (only Paint gets a synchronous reply), so we can't wait to learn whether the reader considers this handled
+	}
+	handled, repaint := s.handler.(AreaHandler).Key(e)
+	if repaint {
+		C.setNeedsDisplay(s.area.view)
+	}
+	if handled {
+		return C.YES
+	}
+	return C.NO
+}
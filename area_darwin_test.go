@@ -0,0 +1,24 @@
+package ui
+
+// #include "area_darwin.h"
+import "C"
+
+import "testing"
+
+func TestCocoaButton(t *testing.T) {
+	tests := []struct {
+		buttonNumber C.int
+		want         uint
+	}{
+		{0, 1}, // left
+		{1, 3}, // right
+		{2, 2}, // middle
+		{3, 4}, // first extra button (XBUTTON1-equivalent); regression test: this used to come out as 5
+		{4, 5}, // second extra button (XBUTTON2-equivalent)
+	}
+	for _, tt := range tests {
+		if got := cocoaButton(tt.buttonNumber); got != tt.want {
+			t.Errorf("cocoaButton(%d) = %d; want %d", tt.buttonNumber, got, tt.want)
+		}
+	}
+}
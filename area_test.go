@@ -0,0 +1,25 @@
+package ui
+
+import "testing"
+
+func TestHeldBits(t *testing.T) {
+	tests := []struct {
+		held []uint
+		want uintptr
+	}{
+		{nil, 0},
+		{[]uint{1}, 1 << 0},
+		{[]uint{2}, 1 << 1},
+		{[]uint{3}, 1 << 2},
+		{[]uint{4}, 1 << 3}, // XBUTTON1-equivalent
+		{[]uint{5}, 1 << 4}, // XBUTTON2-equivalent
+		{[]uint{1, 3, 5}, 1<<0 | 1<<2 | 1<<4},
+		{[]uint{1, 2, 3, 4, 5}, 1<<0 | 1<<1 | 1<<2 | 1<<3 | 1<<4},
+	}
+	for _, tt := range tests {
+		e := MouseEvent{Held: tt.held}
+		if got := e.HeldBits(); got != tt.want {
+			t.Errorf("MouseEvent{Held: %v}.HeldBits() = %#x; want %#x", tt.held, got, tt.want)
+		}
+	}
+}
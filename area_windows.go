@@ -0,0 +1,566 @@
+// 31 july 2014
+
+package ui
+
+// #include <windows.h>
+// #include "winapi_windows.h"
+import "C"
+
+import (
+	"image"
+	"unsafe"
+)
+
+// the Area window class is registered once per process; see sysdata_windows.go for where registerAreaClass() is invoked from mksysdata()
+var areaClassName = C.toUTF16("gouiArea")
+
+// areaWindowData holds the bits of sysData that are specific to rendering and scrolling an Area on Windows.
+type areaWindowData struct {
+	hwnd		C.HWND
+	hwndScroll	C.HWND		// the actual HWND that receives scroll notifications; same as hwnd unless wrapped
+	lastButton	uintptr		// not set or read anywhere; WM_*BUTTONUP is mapped by mapWindowsButton alone, with no pairing against the last WM_*BUTTONDOWN
+	events		chan AreaEvent	// set by Area.Events(); non-nil means Paint/Mouse/Key are delivered here instead of through the AreaHandler
+	done			chan struct{}	// closed by Area.Cancel()
+	cancelled		bool			// set once wmAreaCancel has closed events; touched only on the UI thread, so no lock is needed
+}
+
+// wmAreaCancel is a private message areaWndProc uses to close s.area.events from the UI thread, the only thread that ever sends on it.
+// Area.Cancel() may be called from any goroutine; PostMessageW hands the actual close() over to the thread that owns hwnd, so it can never race with an in-flight send.
+var wmAreaCancel = C.UINT(C.WM_APP) + 1
+
+func registerAreaClass() {
+	var wc C.WNDCLASSW
+	wc.style = C.CS_HREDRAW | C.CS_VREDRAW
+	wc.lpfnWndProc = C.WNDPROC(C.areaWndProc)
+	wc.hInstance = C.getHInstance()
+	wc.hCursor = C.LoadCursorW(nil, C.IDC_ARROW)
+	wc.lpszClassName = areaClassName
+	C.RegisterClassW(&wc)
+}
+
+// makeAreaWindow creates the native window backing an Area, with WS_HSCROLL and WS_VSCROLL always present; setAreaSize() below shows/hides each as needed.
+func (s *sysData) makeAreaWindow(parent C.HWND) error {
+	style := C.WS_CHILD | C.WS_VISIBLE | C.WS_HSCROLL | C.WS_VSCROLL
+	hwnd := C.CreateWindowExW(0,
+		areaClassName, nil,
+		C.DWORD(style),
+		0, 0, 0, 0,
+		parent, nil, C.getHInstance(), nil)
+	if hwnd == nil {
+		return lastError("CreateWindowExW() for Area")
+	}
+	s.area.hwnd = hwnd
+	s.area.hwndScroll = hwnd
+	C.setWindowPointer(hwnd, unsafe.Pointer(s))
+	return nil
+}
+
+// setAreaSize stores the new drawing size and updates the scrollbar ranges; the actual on-screen size of the Area is controlled separately by commitResize(), as documented on Area.
+func (s *sysData) setAreaSize(width int, height int) {
+	var si C.SCROLLINFO
+	si.cbSize = C.UINT(unsafe.Sizeof(si))
+	si.fMask = C.SIF_RANGE | C.SIF_PAGE
+	si.nMin = 0
+
+	clientWidth, clientHeight := s.area.clientSize()
+
+	si.nMax = C.int(width)
+	si.nPage = C.UINT(clientWidth)
+	C.SetScrollInfo(s.area.hwndScroll, C.SB_HORZ, &si, C.TRUE)
+
+	si.nMax = C.int(height)
+	si.nPage = C.UINT(clientHeight)
+	C.SetScrollInfo(s.area.hwndScroll, C.SB_VERT, &si, C.TRUE)
+
+	C.InvalidateRect(s.area.hwnd, nil, C.TRUE)
+}
+
+func (a *areaWindowData) clientSize() (width int, height int) {
+	var r C.RECT
+	C.GetClientRect(a.hwnd, &r)
+	return int(r.right - r.left), int(r.bottom - r.top)
+}
+
+// setAreaEvents records the channels created by Area.Events(); once set, areaPaint/areaMouseEvent/areaWheelEvent/areaKeyEvent deliver through them instead of calling the AreaHandler directly.
+func (s *sysData) setAreaEvents(events chan AreaEvent, done chan struct{}) {
+	s.area.events = events
+	s.area.done = done
+}
+
+// wakeArea asks the UI thread owning this Area to close s.area.events, by way of wmAreaCancel; see that var for why this has to happen on that thread.
+func (s *sysData) wakeArea() {
+	C.PostMessageW(s.area.hwnd, wmAreaCancel, 0, 0)
+}
+
+// areaPostEvent delivers ev on s.area.events, giving up without blocking forever if Area.Cancel() fires first.
+func (s *sysData) areaPostEvent(ev AreaEvent) {
+	if s.area.cancelled { // events is closed; sending on it would panic
+		return
+	}
+	select {
+	case s.area.events <- ev:
+	case <-s.area.done:
+	}
+}
+
+// screenToClient converts a point in screen coordinates (as delivered by WM_MOUSEWHEEL/WM_MOUSEHWHEEL) to client coordinates of hwnd.
+func screenToClient(hwnd C.HWND, x int, y int) image.Point {
+	p := C.POINT{x: C.LONG(x), y: C.LONG(y)}
+	C.ScreenToClient(hwnd, &p)
+	return image.Pt(int(p.x), int(p.y))
+}
+
+// areaScrollOrigin returns the current top-left of the visible viewport into the Area's drawing surface, in Area coordinates.
+func areaScrollOrigin(hwnd C.HWND) image.Point {
+	return image.Pt(
+		int(C.GetScrollPos(hwnd, C.SB_HORZ)),
+		int(C.GetScrollPos(hwnd, C.SB_VERT)))
+}
+
+//export areaWndProc
+func areaWndProc(hwnd C.HWND, msg C.UINT, wparam C.WPARAM, lparam C.LPARAM) C.LRESULT {
+	s := (*sysData)(C.getWindowPointer(hwnd))
+	if s == nil { // messages before WM_CREATE's setWindowPointer call
+		return C.DefWindowProcW(hwnd, msg, wparam, lparam)
+	}
+	switch msg {
+	case C.WM_PAINT:
+		s.areaPaint()
+		return 0
+	case C.WM_HSCROLL, C.WM_VSCROLL:
+		s.areaScroll(msg, wparam)
+		return 0
+	case C.WM_LBUTTONDOWN, C.WM_LBUTTONUP, C.WM_LBUTTONDBLCLK,
+		C.WM_MBUTTONDOWN, C.WM_MBUTTONUP, C.WM_MBUTTONDBLCLK,
+		C.WM_RBUTTONDOWN, C.WM_RBUTTONUP, C.WM_RBUTTONDBLCLK,
+		C.WM_XBUTTONDOWN, C.WM_XBUTTONUP, C.WM_XBUTTONDBLCLK,
+		C.WM_MOUSEMOVE:
+		s.areaMouseEvent(msg, wparam, lparam)
+		if msg == C.WM_XBUTTONDOWN || msg == C.WM_XBUTTONUP || msg == C.WM_XBUTTONDBLCLK {
+			return C.TRUE // per MSDN, these must return TRUE if handled
+		}
+		return 0
+	case C.WM_MOUSEWHEEL, C.WM_MOUSEHWHEEL:
+		s.areaWheelEvent(msg, wparam, lparam)
+		return 0
+	case C.WM_KEYDOWN, C.WM_KEYUP:
+		s.areaKeyEvent(msg, wparam, lparam)
+		return 0
+	case wmAreaCancel:
+		s.areaCancelled()
+		return 0
+	}
+	return C.DefWindowProcW(hwnd, msg, wparam, lparam)
+}
+
+// areaCancelled closes s.area.events in response to wmAreaCancel; it runs on the UI thread, the only thread that ever sends on events, so it can close it without racing a send.
+func (s *sysData) areaCancelled() {
+	if s.area.events == nil || s.area.cancelled {
+		return
+	}
+	s.area.cancelled = true
+	close(s.area.events)
+}
+
+// numButtons reports the number of buttons Windows advertises for the primary pointing device, including XBUTTON1/XBUTTON2 if present.
+func (s *sysData) numButtons() uint {
+	n := C.GetSystemMetrics(C.SM_CMOUSEBUTTONS)
+	if n < 3 { // some ancient or virtual devices misreport this; Area always presents at least left/middle/right
+		return 3
+	}
+	return uint(n)
+}
+
+// areaPaint handles WM_PAINT.
+// If the AreaHandler also implements AreaRegionHandler, and the pending update region decomposes into more than one rectangle, every sub-rectangle is painted (and blitted) individually; otherwise the AreaHandler is called once with the bounding rectangle BeginPaint() reports, exactly as before.
+func (s *sysData) areaPaint() {
+	regions := s.areaRegionsIfWanted()
+
+	var ps C.PAINTSTRUCT
+	C.BeginPaint(s.area.hwnd, &ps)
+	defer C.EndPaint(s.area.hwnd, &ps)
+
+	origin := areaScrollOrigin(s.area.hwndScroll)
+
+	if len(regions) > 1 {
+		for i := range regions {
+			regions[i] = regions[i].Add(origin)
+		}
+		s.areaPaintRegions(ps.hdc, regions, origin)
+		return
+	}
+
+	rect := image.Rect(
+		int(ps.rcPaint.left), int(ps.rcPaint.top),
+		int(ps.rcPaint.right), int(ps.rcPaint.bottom)).Add(origin)
+
+	img, ok := s.areaPaintImage(rect)
+	if !ok {
+		return // Area.Cancel() fired while this paint was in flight; nothing left to blit
+	}
+	if img == nil || img.Rect.Dx() != rect.Dx() || img.Rect.Dy() != rect.Dy() {
+		panic("Area.Paint() returned nil or an image of the wrong size")
+	}
+	s.blitAreaImage(ps.hdc, img, rect, origin)
+}
+
+// areaRegionsIfWanted decomposes the pending update region into its individual rectangles via GetUpdateRgn/GetRegionData, but only when there is an AreaRegionHandler around to make use of them; channel mode (Area.Events()) has no region-aware counterpart, so it is left alone.
+func (s *sysData) areaRegionsIfWanted() []image.Rectangle {
+	if s.area.events != nil {
+		return nil
+	}
+	if _, ok := s.handler.(AreaRegionHandler); !ok {
+		return nil
+	}
+	return s.areaUpdateRegions()
+}
+
+// areaUpdateRegions reads the HWND's pending update region and decomposes it into one image.Rectangle per rectangle GetRegionData reports, in client coordinates.
+func (s *sysData) areaUpdateRegions() []image.Rectangle {
+	hrgn := C.CreateRectRgn(0, 0, 0, 0)
+	defer C.DeleteObject(C.HGDIOBJ(hrgn))
+	if C.GetUpdateRgn(s.area.hwnd, hrgn, C.FALSE) == C.NULLREGION {
+		return nil
+	}
+
+	need := C.GetRegionData(hrgn, 0, nil)
+	if need == 0 {
+		return nil
+	}
+	buf := make([]byte, need)
+	rd := (*C.RGNDATA)(unsafe.Pointer(&buf[0]))
+	rd.rdh.dwSize = C.DWORD(unsafe.Sizeof(rd.rdh))
+	if C.GetRegionData(hrgn, need, rd) == 0 {
+		return nil
+	}
+
+	n := int(rd.rdh.nCount)
+	rects := unsafe.Slice((*C.RECT)(unsafe.Pointer(&rd.Buffer[0])), n)
+	regions := make([]image.Rectangle, n)
+	for i, r := range rects {
+		regions[i] = image.Rect(int(r.left), int(r.top), int(r.right), int(r.bottom))
+	}
+	return regions
+}
+
+// areaPaintRegions calls AreaRegionHandler.PaintRegions with regions (already in Area coordinates) and blits each returned image to its corresponding rectangle.
+func (s *sysData) areaPaintRegions(hdc C.HDC, regions []image.Rectangle, origin image.Point) {
+	images := s.handler.(AreaRegionHandler).PaintRegions(regions)
+	for _, r := range regions {
+		img := images[r]
+		if img == nil || img.Rect.Dx() != r.Dx() || img.Rect.Dy() != r.Dy() {
+			panic("Area.PaintRegions() did not return a correctly sized image for every region")
+		}
+		s.blitAreaImage(hdc, img, r, origin)
+	}
+}
+
+// blitAreaImage draws img, which must be the same size as rect (an Area-coordinate rectangle), to hdc by way of StretchDIBits; origin is the current scroll position, used to translate rect back to client coordinates.
+func (s *sysData) blitAreaImage(hdc C.HDC, img *image.RGBA, rect image.Rectangle, origin image.Point) {
+	var bmi C.BITMAPINFO
+	bmi.bmiHeader.biSize = C.DWORD(unsafe.Sizeof(bmi.bmiHeader))
+	bmi.bmiHeader.biWidth = C.LONG(img.Rect.Dx())
+	bmi.bmiHeader.biHeight = C.LONG(-img.Rect.Dy()) // negative: top-down DIB, matching image.RGBA's row order
+	bmi.bmiHeader.biPlanes = 1
+	bmi.bmiHeader.biBitCount = 32
+	bmi.bmiHeader.biCompression = C.BI_RGB
+
+	dstX := rect.Min.X - origin.X
+	dstY := rect.Min.Y - origin.Y
+	C.StretchDIBits(hdc,
+		C.int(dstX), C.int(dstY), C.int(rect.Dx()), C.int(rect.Dy()),
+		0, 0, C.int(rect.Dx()), C.int(rect.Dy()),
+		unsafe.Pointer(pixelData(img)), &bmi, C.DIB_RGB_COLORS, C.SRCCOPY)
+}
+
+// invalidateAreaRect marks rect (in Area coordinates) as needing to be redrawn; Windows client coordinates are rect shifted back by the current scroll origin.
+func (s *sysData) invalidateAreaRect(rect image.Rectangle) {
+	origin := areaScrollOrigin(s.area.hwndScroll)
+	r := rect.Sub(origin)
+	winRect := C.RECT{
+		left:	C.LONG(r.Min.X),
+		top:	C.LONG(r.Min.Y),
+		right:	C.LONG(r.Max.X),
+		bottom:	C.LONG(r.Max.Y),
+	}
+	C.InvalidateRect(s.area.hwnd, &winRect, C.TRUE)
+}
+
+// invalidateArea marks the entire Area as needing to be redrawn.
+func (s *sysData) invalidateArea() {
+	C.InvalidateRect(s.area.hwnd, nil, C.TRUE)
+}
+
+// areaPaintImage obtains the image to paint rect with, either by calling AreaHandler.Paint directly or, once Area.Events() has switched this Area into channel mode, by delivering a PaintEvent and blocking for its Reply.
+// ok is false only if Area.Cancel() fired before a reply arrived, in which case img must not be used.
+func (s *sysData) areaPaintImage(rect image.Rectangle) (img *image.RGBA, ok bool) {
+	if s.area.events == nil {
+		return s.handler.(AreaHandler).Paint(rect), true
+	}
+	if s.area.cancelled { // events is closed; sending on it would panic
+		return nil, false
+	}
+	reply := make(chan *image.RGBA)
+	select {
+	case s.area.events <- PaintEvent{Rect: rect, reply: reply}:
+	case <-s.area.done:
+		return nil, false
+	}
+	select {
+	case img = <-reply:
+		return img, true
+	case <-s.area.done:
+		return nil, false
+	}
+}
+
+func (s *sysData) areaScroll(msg C.UINT, wparam C.WPARAM) {
+	bar := C.SB_HORZ
+	if msg == C.WM_VSCROLL {
+		bar = C.SB_VERT
+	}
+
+	var si C.SCROLLINFO
+	si.cbSize = C.UINT(unsafe.Sizeof(si))
+	si.fMask = C.SIF_ALL
+	C.GetScrollInfo(s.area.hwndScroll, C.int(bar), &si)
+
+	pos := int(si.nPos)
+	switch C.LOWORD(C.DWORD(wparam)) {
+	case C.SB_LINEUP:
+		pos -= 8
+	case C.SB_LINEDOWN:
+		pos += 8
+	case C.SB_PAGEUP:
+		pos -= int(si.nPage)
+	case C.SB_PAGEDOWN:
+		pos += int(si.nPage)
+	case C.SB_THUMBTRACK, C.SB_THUMBPOSITION:
+		pos = int(si.nTrackPos)
+	}
+
+	si.fMask = C.SIF_POS
+	si.nPos = C.int(pos)
+	C.SetScrollInfo(s.area.hwndScroll, C.int(bar), &si, C.TRUE)
+	C.InvalidateRect(s.area.hwnd, nil, C.TRUE)
+}
+
+// mapWindowsButton converts a Win32 button-related message into the button ID scheme documented on MouseEvent.
+// For WM_XBUTTON*, wparam is needed too, since XBUTTON1 and XBUTTON2 share the same messages (distinguished by GET_XBUTTON_WPARAM).
+func mapWindowsButton(msg C.UINT, wparam C.WPARAM) uint {
+	switch msg {
+	case C.WM_LBUTTONDOWN, C.WM_LBUTTONUP, C.WM_LBUTTONDBLCLK:
+		return 1
+	case C.WM_MBUTTONDOWN, C.WM_MBUTTONUP, C.WM_MBUTTONDBLCLK:
+		return 2
+	case C.WM_RBUTTONDOWN, C.WM_RBUTTONUP, C.WM_RBUTTONDBLCLK:
+		return 3
+	case C.WM_XBUTTONDOWN, C.WM_XBUTTONUP, C.WM_XBUTTONDBLCLK:
+		if C.GET_XBUTTON_WPARAM(wparam) == C.XBUTTON2 {
+			return 5
+		}
+		return 4 // XBUTTON1
+	}
+	return 0
+}
+
+// windowsModifiers queries the VK_L*/VK_R* side-specific virtual keys directly, rather than the side-agnostic VK_CONTROL/VK_MENU/VK_SHIFT; Modifiers' coarse Ctrl/Alt/Shift bits fall out automatically since they're defined as the OR of their sides.
+func windowsModifiers(wparam C.WPARAM) Modifiers {
+	var m Modifiers
+	if C.GetKeyState(C.VK_LCONTROL) < 0 {
+		m |= LeftCtrl
+	}
+	if C.GetKeyState(C.VK_RCONTROL) < 0 {
+		m |= RightCtrl
+	}
+	if C.GetKeyState(C.VK_LMENU) < 0 {
+		m |= LeftAlt
+	}
+	if C.GetKeyState(C.VK_RMENU) < 0 {
+		m |= RightAlt
+	}
+	if C.GetKeyState(C.VK_LSHIFT) < 0 {
+		m |= LeftShift
+	}
+	if C.GetKeyState(C.VK_RSHIFT) < 0 {
+		m |= RightShift
+	}
+	if C.GetKeyState(C.VK_LWIN) < 0 {
+		m |= LeftSuper
+	}
+	if C.GetKeyState(C.VK_RWIN) < 0 {
+		m |= RightSuper
+	}
+	return m
+}
+
+func windowsHeldButtons(wparam C.WPARAM) []uint {
+	var held []uint
+	flags := uintptr(C.LOWORD(C.DWORD(wparam)))
+	if flags&C.MK_LBUTTON != 0 {
+		held = append(held, 1)
+	}
+	if flags&C.MK_MBUTTON != 0 {
+		held = append(held, 2)
+	}
+	if flags&C.MK_RBUTTON != 0 {
+		held = append(held, 3)
+	}
+	if flags&C.MK_XBUTTON1 != 0 {
+		held = append(held, 4)
+	}
+	if flags&C.MK_XBUTTON2 != 0 {
+		held = append(held, 5)
+	}
+	return held
+}
+
+func (s *sysData) areaMouseEvent(msg C.UINT, wparam C.WPARAM, lparam C.LPARAM) {
+	origin := areaScrollOrigin(s.area.hwndScroll)
+	pos := image.Pt(int(C.GET_X_LPARAM(lparam)), int(C.GET_Y_LPARAM(lparam))).Add(origin)
+
+	e := MouseEvent{
+		Pos:		pos,
+		Modifiers:	windowsModifiers(wparam),
+		Held:		windowsHeldButtons(wparam),
+	}
+
+	switch msg {
+	case C.WM_MOUSEMOVE:
+		// Down, Up both zero
+	case C.WM_LBUTTONDOWN, C.WM_MBUTTONDOWN, C.WM_RBUTTONDOWN, C.WM_XBUTTONDOWN:
+		e.Down = mapWindowsButton(msg, wparam)
+		e.Count = 1
+	case C.WM_LBUTTONDBLCLK, C.WM_MBUTTONDBLCLK, C.WM_RBUTTONDBLCLK, C.WM_XBUTTONDBLCLK:
+		e.Down = mapWindowsButton(msg, wparam)
+		e.Count = 2
+	case C.WM_LBUTTONUP, C.WM_MBUTTONUP, C.WM_RBUTTONUP, C.WM_XBUTTONUP:
+		e.Up = mapWindowsButton(msg, wparam)
+	}
+
+	if s.area.events != nil {
+		s.areaPostEvent(e)
+		return
+	}
+	repaint := s.handler.(AreaHandler).Mouse(e)
+	if repaint {
+		C.InvalidateRect(s.area.hwnd, nil, C.TRUE)
+	}
+}
+
+// areaWheelEvent handles WM_MOUSEWHEEL (vertical) and WM_MOUSEHWHEEL (horizontal); wparam's high-order word is a signed multiple of WHEEL_DELTA, which we convert to whole-or-fractional notches.
+func (s *sysData) areaWheelEvent(msg C.UINT, wparam C.WPARAM, lparam C.LPARAM) {
+	origin := areaScrollOrigin(s.area.hwndScroll)
+	// WM_MOUSEWHEEL/WM_MOUSEHWHEEL report the position in screen coordinates, not client coordinates, unlike the other mouse messages.
+	pos := screenToClient(s.area.hwnd, int(C.GET_X_LPARAM(lparam)), int(C.GET_Y_LPARAM(lparam))).Add(origin)
+
+	notches := float64(C.GET_WHEEL_DELTA_WPARAM(wparam)) / float64(C.WHEEL_DELTA)
+	e := MouseEvent{
+		Pos:		pos,
+		Modifiers:	windowsModifiers(wparam),
+		Held:		windowsHeldButtons(wparam),
+	}
+	if msg == C.WM_MOUSEHWHEEL {
+		e.WheelX = notches
+	} else {
+		e.WheelY = notches
+	}
+
+	if s.area.events != nil {
+		s.areaPostEvent(e)
+		return
+	}
+	repaint := s.handler.(AreaHandler).Mouse(e)
+	if repaint {
+		C.InvalidateRect(s.area.hwnd, nil, C.TRUE)
+	}
+}
+
+// translateWindowsKey maps a WM_KEYDOWN/WM_KEYUP virtual-key code (and, for Enter, the extended-key bit of lparam) to Area's Key/ExtKey model.
+// The typewriter punctuation keys (` - = [ ] \ ; ' , . /) are not translated, and with Num Lock off the numpad keys arrive as the navigation keys they double as and are translated as those; these and any other key not listed below yield (0, 0).
+func translateWindowsKey(vk C.DWORD, lparam C.DWORD) (key byte, extkey ExtKey) {
+	switch {
+	case vk >= 'A' && vk <= 'Z':
+		return byte(vk - 'A' + 'a'), 0
+	case vk >= '0' && vk <= '9':
+		return byte(vk), 0 // digits already line up with ASCII
+	}
+	switch vk {
+	case C.VK_SPACE:
+		return ' ', 0
+	case C.VK_TAB:
+		return '\t', 0
+	case C.VK_RETURN:
+		if lparam&(1<<24) != 0 { // extended-key bit: the numpad Enter key
+			return 0, NEnter
+		}
+		return '\n', 0
+	case C.VK_BACK:
+		return '\b', 0
+	case C.VK_ESCAPE:
+		return 0, Escape
+	case C.VK_INSERT:
+		return 0, Insert
+	case C.VK_DELETE:
+		return 0, Delete
+	case C.VK_HOME:
+		return 0, Home
+	case C.VK_END:
+		return 0, End
+	case C.VK_PRIOR:
+		return 0, PageUp
+	case C.VK_NEXT:
+		return 0, PageDown
+	case C.VK_UP:
+		return 0, Up
+	case C.VK_DOWN:
+		return 0, Down
+	case C.VK_LEFT:
+		return 0, Left
+	case C.VK_RIGHT:
+		return 0, Right
+	case C.VK_F1, C.VK_F2, C.VK_F3, C.VK_F4, C.VK_F5, C.VK_F6,
+		C.VK_F7, C.VK_F8, C.VK_F9, C.VK_F10, C.VK_F11, C.VK_F12:
+		return 0, F1 + ExtKey(vk-C.VK_F1)
+	case C.VK_NUMPAD0, C.VK_NUMPAD1, C.VK_NUMPAD2, C.VK_NUMPAD3, C.VK_NUMPAD4,
+		C.VK_NUMPAD5, C.VK_NUMPAD6, C.VK_NUMPAD7, C.VK_NUMPAD8, C.VK_NUMPAD9:
+		return 0, N0 + ExtKey(vk-C.VK_NUMPAD0)
+	case C.VK_DECIMAL:
+		return 0, NDot
+	case C.VK_ADD:
+		return 0, NAdd
+	case C.VK_SUBTRACT:
+		return 0, NSubtract
+	case C.VK_MULTIPLY:
+		return 0, NMultiply
+	case C.VK_DIVIDE:
+		return 0, NDivide
+	case C.VK_LWIN:
+		return 0, SuperLeft
+	case C.VK_RWIN:
+		return 0, SuperRight
+	}
+	return 0, 0
+}
+
+func (s *sysData) areaKeyEvent(msg C.UINT, wparam C.WPARAM, lparam C.LPARAM) {
+	key, extkey := translateWindowsKey(C.DWORD(wparam), C.DWORD(lparam))
+	if key == 0 && extkey == 0 {
+		return // not representable by Area's key model; no KeyEvent is sent, and areaWndProc returns 0 for it without calling DefWindowProc, as for every WM_KEYDOWN/WM_KEYUP
+	}
+	e := KeyEvent{
+		Key:		key,
+		ExtKey:	extkey,
+		Modifiers:	windowsModifiers(wparam),
+		Up:		msg == C.WM_KEYUP,
+	}
+	if s.area.events != nil {
+		s.areaPostEvent(e)
+		return
+	}
+	handled, repaint := s.handler.(AreaHandler).Key(e)
+	_ = handled // Windows does not give us a reliable way to veto further processing of WM_KEYDOWN from within WndProc
+	if repaint {
+		C.InvalidateRect(s.area.hwnd, nil, C.TRUE)
+	}
+}
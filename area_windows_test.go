@@ -0,0 +1,45 @@
+package ui
+
+// #include <windows.h>
+// #include "winapi_windows.h"
+import "C"
+
+import "testing"
+
+func TestTranslateWindowsKey(t *testing.T) {
+	tests := []struct {
+		vk         C.DWORD
+		wantKey    byte
+		wantExtKey ExtKey
+	}{
+		{'A', 'a', 0},
+		{'Z', 'z', 0},
+		{'0', '0', 0}, // regression test: this used to come out as 'P'
+		{'5', '5', 0},
+		{'9', '9', 0}, // regression test: this used to come out as 'Y'
+		{C.VK_SPACE, ' ', 0},
+		{C.VK_TAB, '\t', 0},
+		{C.VK_RETURN, '\n', 0},
+		{C.VK_BACK, '\b', 0},
+		{C.VK_ESCAPE, 0, Escape},
+		{C.VK_DELETE, 0, Delete},
+		{C.VK_HOME, 0, Home},
+		{C.VK_END, 0, End},
+		{C.VK_UP, 0, Up},
+		{C.VK_DOWN, 0, Down},
+		{C.VK_LEFT, 0, Left},
+		{C.VK_RIGHT, 0, Right},
+		{C.VK_F1, 0, F1},
+		{C.VK_F12, 0, F12},
+		{C.VK_NUMPAD0, 0, N0},
+		{C.VK_NUMPAD9, 0, N9},
+		{C.VK_LWIN, 0, SuperLeft},
+		{C.VK_RWIN, 0, SuperRight},
+	}
+	for _, tt := range tests {
+		key, extkey := translateWindowsKey(tt.vk, 0)
+		if key != tt.wantKey || extkey != tt.wantExtKey {
+			t.Errorf("translateWindowsKey(%#x, 0) = (%q, %v); want (%q, %v)", tt.vk, key, extkey, tt.wantKey, tt.wantExtKey)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// 4 august 2014
+
+package ui
+
+import (
+	"image"
+	"sync"
+)
+
+// TextArea represents a blank canvas, just like Area, but one that additionally receives character-level text input after the system has finished any IME composition, rather than Area's scan-code oriented Key/ExtKey events.
+// Everything documented on Area regarding size, scrollbars, and the coordinate system applies equally to TextArea.
+//
+// To handle events to the TextArea, a TextArea must be paired with a TextAreaHandler.
+// See TextAreaHandler for details.
+//
+// TextAreas are implemented on Windows and Mac OS X.
+// TODO: no GTK+ backend exists yet (IBus/XIM commit/preedit-changed handling is not implemented); until one lands, TextArea is unavailable on that platform.
+type TextArea struct {
+	lock		sync.Mutex
+	created	bool
+	sysData	*sysData
+	handler	TextAreaHandler
+	initwidth	int
+	initheight	int
+}
+
+// TextAreaHandler represents the events that a TextArea should respond to.
+// You are responsible for the thread safety of any members of the actual type that implements this interface.
+type TextAreaHandler interface {
+	// Paint behaves identically to AreaHandler.Paint; see that method for details.
+	Paint(cliprect image.Rectangle) *image.RGBA
+
+	// Mouse behaves identically to AreaHandler.Mouse; see that method for details.
+	// The one difference: on Mac OS X, wheel events delivered to a TextArea have an empty Held.
+	Mouse(e MouseEvent) (repaint bool)
+
+	// TextInput is called with one or more runes once the system has finished processing a keystroke (and any IME composition it may have started).
+	// Unlike Area's Key, TextInput is never called for a keystroke that produced no character (a bare modifier, a function key, and so on); those are not delivered to TextArea at all.
+	// If repaint is true, the TextArea is marked as needing to be redrawn.
+	TextInput(runes []rune) (repaint bool)
+
+	// CompositionUpdate is called whenever the system's input method updates its current preedit (composition) string, such as while entering a CJK character via an IME.
+	// preedit is the full text of the in-progress composition; cursor is the rune offset within preedit of the insertion point the IME wants to show.
+	// TextInput is not called for any part of a composition until CompositionEnd commits it.
+	CompositionUpdate(preedit string, cursor int)
+
+	// CompositionEnd is called when an in-progress composition finishes, whether by commission (in which case the committed text arrives via TextInput) or cancellation.
+	CompositionEnd()
+}
+
+func checkTextAreaSize(width int, height int, which string) {
+	checkAreaSize(width, height, which)
+}
+
+// NewTextArea creates a new TextArea with the given size and handler.
+// It panics if handler is nil or if width or height is zero or negative.
+func NewTextArea(width int, height int, handler TextAreaHandler) *TextArea {
+	checkTextAreaSize(width, height, "NewTextArea()")
+	if handler == nil {
+		panic("handler passed to NewTextArea() must not be nil")
+	}
+	return &TextArea{
+		sysData:		mksysdata(c_textarea),
+		handler:		handler,
+		initwidth:		width,
+		initheight:		height,
+	}
+}
+
+// SetSize behaves identically to Area.SetSize; see that method for details.
+func (t *TextArea) SetSize(width int, height int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	checkTextAreaSize(width, height, "TextArea.SetSize()")
+	if t.created {
+		t.sysData.setAreaSize(width, height)
+		return
+	}
+	t.initwidth = width
+	t.initheight = height
+}
+
+// SetIMEPosition tells the system where, in TextArea coordinates, to anchor any IME candidate or preedit window it shows while the user is composing text (for instance, next to a caret TextAreaHandler.Paint has drawn).
+// Systems without an active IME ignore this call.
+func (t *TextArea) SetIMEPosition(p image.Point) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.created {
+		t.sysData.setIMEPosition(p)
+	}
+}
+
+func (t *TextArea) make(window *sysData) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.sysData.handler = t.handler
+	err := t.sysData.make(window)
+	if err != nil {
+		return err
+	}
+	t.sysData.setAreaSize(t.initwidth, t.initheight)
+	t.created = true
+	return nil
+}
+
+func (t *TextArea) setRect(x int, y int, width int, height int, rr *[]resizerequest) {
+	*rr = append(*rr, resizerequest{
+		sysData:	t.sysData,
+		x:		x,
+		y:		y,
+		width:	width,
+		height:	height,
+	})
+}
+
+func (t *TextArea) preferredSize() (width int, height int) {
+	return t.sysData.preferredSize()
+}
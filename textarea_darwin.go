@@ -0,0 +1,120 @@
+// 5 august 2014
+
+package ui
+
+// #cgo LDFLAGS: -framework Cocoa
+// #include "area_darwin.h"
+// #include "textarea_darwin.h"
+import "C"
+
+import (
+	"image"
+	"unicode/utf16"
+	"unsafe"
+)
+
+// textareaCocoaData holds the bits of sysData that are specific to rendering, scrolling, and IME handling for a TextArea on Mac OS X.
+type textareaCocoaData struct {
+	scrollview	C.id	// NSScrollView
+	view		C.id	// goTextAreaView, the NSScrollView's documentView
+}
+
+// makeTextAreaView creates the NSScrollView/goTextAreaView pair backing a TextArea; goTextAreaView (textarea_darwin.m) calls back into textareaDraw, textareaMouseEvent/textareaWheelEvent, and the NSTextInputClient-driven textareaTextInput/textareaCompositionUpdate/textareaCompositionEnd below by way of its stashed sysData pointer.
+func (s *sysData) makeTextAreaView(parent C.id) error {
+	s.textarea.scrollview = C.newTextAreaScrollView(unsafe.Pointer(s))
+	s.textarea.view = C.textareaScrollViewDocumentView(s.textarea.scrollview)
+	C.addSubview(parent, s.textarea.scrollview)
+	return nil
+}
+
+func (s *sysData) setIMEPosition(p image.Point) {
+	C.textareaSetIMEPosition(s.textarea.view, C.double(p.X), C.double(p.Y))
+}
+
+//export textareaDraw
+func textareaDraw(sd unsafe.Pointer, x, y, w, h C.double) C.TextAreaCImage {
+	s := (*sysData)(sd)
+	rect := image.Rect(int(x), int(y), int(x+w), int(y+h))
+	img := s.handler.(TextAreaHandler).Paint(rect)
+	if img == nil || img.Rect.Dx() != rect.Dx() || img.Rect.Dy() != rect.Dy() {
+		panic("TextArea.Paint() returned nil or an image of the wrong size")
+	}
+	return C.TextAreaCImage{
+		pixels:	unsafe.Pointer(pixelData(img)),
+		width:	C.int(img.Rect.Dx()),
+		height:	C.int(img.Rect.Dy()),
+		stride:	C.int(img.Stride),
+	}
+}
+
+//export textareaMouseEvent
+func textareaMouseEvent(sd unsafe.Pointer, kind C.int, x, y C.double, count C.int, buttonNumber C.int, heldMask C.uint32_t, flags C.uint64_t) {
+	s := (*sysData)(sd)
+	button := cocoaButton(buttonNumber)
+	e := MouseEvent{
+		Pos:		image.Pt(int(x), int(y)),
+		Modifiers:	cocoaModifiers(flags),
+	}
+	for b := uint(1); b <= 5; b++ {
+		if heldMask&(1<<(b-1)) != 0 {
+			e.Held = append(e.Held, b)
+		}
+	}
+	switch kind {
+	case C.areaEventMouseDown:
+		e.Down = button
+		e.Count = uint(count)
+	case C.areaEventMouseUp:
+		e.Up = button
+	case C.areaEventMouseMoved, C.areaEventMouseDragged:
+		// Down, Up both zero
+	}
+	repaint := s.handler.(TextAreaHandler).Mouse(e)
+	if repaint {
+		C.setNeedsDisplay(s.textarea.view)
+	}
+}
+
+//export textareaWheelEvent
+func textareaWheelEvent(sd unsafe.Pointer, x, y, deltaX, deltaY C.double, flags C.uint64_t) {
+	s := (*sysData)(sd)
+	e := MouseEvent{
+		Pos:		image.Pt(int(x), int(y)),
+		Modifiers:	cocoaModifiers(flags),
+		WheelX:	float64(deltaX),
+		WheelY:	float64(deltaY),
+	}
+	repaint := s.handler.(TextAreaHandler).Mouse(e)
+	if repaint {
+		C.setNeedsDisplay(s.textarea.view)
+	}
+}
+
+// cUnicharsToRunes converts a run of Cocoa unichars (UTF-16 code units) handed across cgo into Go runes, combining any surrogate pairs along the way.
+func cUnicharsToRunes(p *C.unichar, n C.int) []rune {
+	units := make([]uint16, int(n))
+	src := unsafe.Slice((*uint16)(unsafe.Pointer(p)), int(n))
+	copy(units, src)
+	return utf16.Decode(units)
+}
+
+//export textareaTextInput
+func textareaTextInput(sd unsafe.Pointer, runes *C.unichar, nrunes C.int) {
+	s := (*sysData)(sd)
+	repaint := s.handler.(TextAreaHandler).TextInput(cUnicharsToRunes(runes, nrunes))
+	if repaint {
+		C.setNeedsDisplay(s.textarea.view)
+	}
+}
+
+//export textareaCompositionUpdate
+func textareaCompositionUpdate(sd unsafe.Pointer, preedit *C.unichar, npreedit C.int, cursor C.int) {
+	s := (*sysData)(sd)
+	s.handler.(TextAreaHandler).CompositionUpdate(string(cUnicharsToRunes(preedit, npreedit)), int(cursor))
+}
+
+//export textareaCompositionEnd
+func textareaCompositionEnd(sd unsafe.Pointer) {
+	s := (*sysData)(sd)
+	s.handler.(TextAreaHandler).CompositionEnd()
+}
@@ -0,0 +1,257 @@
+// 5 august 2014
+
+package ui
+
+// #include <windows.h>
+// #include "winapi_windows.h"
+import "C"
+
+import (
+	"image"
+	"unicode/utf16"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// the TextArea window class is registered once per process; see sysdata_windows.go for where registerTextAreaClass() is invoked from mksysdata()
+var textareaClassName = C.toUTF16("gouiTextArea")
+
+// textareaWindowData holds the bits of sysData that are specific to rendering, scrolling, and IME handling for a TextArea on Windows.
+type textareaWindowData struct {
+	hwnd		C.HWND
+	hwndScroll	C.HWND	// the actual HWND that receives scroll notifications; same as hwnd unless wrapped
+	imePos		C.POINT	// last position passed to SetIMEPosition(), applied to each new composition window
+	pendingHighSurrogate	uint16	// a WM_CHAR high surrogate buffered until its low surrogate arrives in the next WM_CHAR; 0 when none is pending
+}
+
+func registerTextAreaClass() {
+	var wc C.WNDCLASSW
+	wc.style = C.CS_HREDRAW | C.CS_VREDRAW
+	wc.lpfnWndProc = C.WNDPROC(C.textareaWndProc)
+	wc.hInstance = C.getHInstance()
+	wc.hCursor = C.LoadCursorW(nil, C.IDC_IBEAM)
+	wc.lpszClassName = textareaClassName
+	C.RegisterClassW(&wc)
+}
+
+// makeTextAreaWindow creates the native window backing a TextArea, with WS_HSCROLL and WS_VSCROLL always present; setAreaSize() below shows/hides each as needed.
+func (s *sysData) makeTextAreaWindow(parent C.HWND) error {
+	style := C.WS_CHILD | C.WS_VISIBLE | C.WS_HSCROLL | C.WS_VSCROLL
+	hwnd := C.CreateWindowExW(0,
+		textareaClassName, nil,
+		C.DWORD(style),
+		0, 0, 0, 0,
+		parent, nil, C.getHInstance(), nil)
+	if hwnd == nil {
+		return lastError("CreateWindowExW() for TextArea")
+	}
+	s.textarea.hwnd = hwnd
+	s.textarea.hwndScroll = hwnd
+	C.setWindowPointer(hwnd, unsafe.Pointer(s))
+	return nil
+}
+
+// setIMEPosition stashes p and, if a composition is in progress right now, repositions its composition window immediately; otherwise the stashed value is applied the next time WM_IME_STARTCOMPOSITION arrives.
+func (s *sysData) setIMEPosition(p image.Point) {
+	s.textarea.imePos = C.POINT{x: C.LONG(p.X), y: C.LONG(p.Y)}
+	s.positionIMECompositionWindow()
+}
+
+func (s *sysData) positionIMECompositionWindow() {
+	himc := C.ImmGetContext(s.textarea.hwnd)
+	if himc == nil {
+		return
+	}
+	defer C.ImmReleaseContext(s.textarea.hwnd, himc)
+
+	var cf C.COMPOSITIONFORM
+	cf.dwStyle = C.CFS_POINT
+	origin := areaScrollOrigin(s.textarea.hwndScroll) // imePos is in TextArea coordinates; the composition window wants client coordinates
+	cf.ptCurrentPos = C.POINT{
+		x:	s.textarea.imePos.x - C.LONG(origin.X),
+		y:	s.textarea.imePos.y - C.LONG(origin.Y),
+	}
+	C.ImmSetCompositionWindow(himc, &cf)
+}
+
+//export textareaWndProc
+func textareaWndProc(hwnd C.HWND, msg C.UINT, wparam C.WPARAM, lparam C.LPARAM) C.LRESULT {
+	s := (*sysData)(C.getWindowPointer(hwnd))
+	if s == nil { // messages before WM_CREATE's setWindowPointer call
+		return C.DefWindowProcW(hwnd, msg, wparam, lparam)
+	}
+	switch msg {
+	case C.WM_PAINT:
+		s.textareaPaint()
+		return 0
+	case C.WM_HSCROLL, C.WM_VSCROLL:
+		s.areaScroll(msg, wparam) // shared with Area; operates purely on the scroll bars of hwndScroll
+		return 0
+	case C.WM_LBUTTONDOWN, C.WM_LBUTTONUP, C.WM_LBUTTONDBLCLK,
+		C.WM_MBUTTONDOWN, C.WM_MBUTTONUP, C.WM_MBUTTONDBLCLK,
+		C.WM_RBUTTONDOWN, C.WM_RBUTTONUP, C.WM_RBUTTONDBLCLK,
+		C.WM_XBUTTONDOWN, C.WM_XBUTTONUP, C.WM_XBUTTONDBLCLK,
+		C.WM_MOUSEMOVE:
+		s.textareaMouseEvent(msg, wparam, lparam)
+		if msg == C.WM_XBUTTONDOWN || msg == C.WM_XBUTTONUP || msg == C.WM_XBUTTONDBLCLK {
+			return C.TRUE // per MSDN, these must return TRUE if handled
+		}
+		return 0
+	case C.WM_MOUSEWHEEL, C.WM_MOUSEHWHEEL:
+		s.textareaWheelEvent(msg, wparam, lparam)
+		return 0
+	case C.WM_CHAR:
+		s.textareaChar(wparam)
+		return 0
+	case C.WM_IME_STARTCOMPOSITION:
+		s.positionIMECompositionWindow()
+		return C.DefWindowProcW(hwnd, msg, wparam, lparam) // let the system still draw the default composition UI
+	case C.WM_IME_COMPOSITION:
+		s.textareaComposition(lparam)
+		return C.DefWindowProcW(hwnd, msg, wparam, lparam)
+	case C.WM_IME_ENDCOMPOSITION:
+		s.handler.(TextAreaHandler).CompositionEnd()
+		return C.DefWindowProcW(hwnd, msg, wparam, lparam)
+	}
+	return C.DefWindowProcW(hwnd, msg, wparam, lparam)
+}
+
+// textareaPaint behaves like areaPaint's single-rectangle path, but delivers to a TextAreaHandler; it has no PaintRegions or Events() counterpart.
+func (s *sysData) textareaPaint() {
+	var ps C.PAINTSTRUCT
+	C.BeginPaint(s.textarea.hwnd, &ps)
+	defer C.EndPaint(s.textarea.hwnd, &ps)
+
+	origin := areaScrollOrigin(s.textarea.hwndScroll)
+	rect := image.Rect(
+		int(ps.rcPaint.left), int(ps.rcPaint.top),
+		int(ps.rcPaint.right), int(ps.rcPaint.bottom)).Add(origin)
+
+	img := s.handler.(TextAreaHandler).Paint(rect)
+	if img == nil || img.Rect.Dx() != rect.Dx() || img.Rect.Dy() != rect.Dy() {
+		panic("TextArea.Paint() returned nil or an image of the wrong size")
+	}
+
+	var bmi C.BITMAPINFO
+	bmi.bmiHeader.biSize = C.DWORD(unsafe.Sizeof(bmi.bmiHeader))
+	bmi.bmiHeader.biWidth = C.LONG(img.Rect.Dx())
+	bmi.bmiHeader.biHeight = C.LONG(-img.Rect.Dy()) // negative: top-down DIB, matching image.RGBA's row order
+	bmi.bmiHeader.biPlanes = 1
+	bmi.bmiHeader.biBitCount = 32
+	bmi.bmiHeader.biCompression = C.BI_RGB
+
+	dstX := rect.Min.X - origin.X
+	dstY := rect.Min.Y - origin.Y
+	C.StretchDIBits(ps.hdc,
+		C.int(dstX), C.int(dstY), C.int(rect.Dx()), C.int(rect.Dy()),
+		0, 0, C.int(rect.Dx()), C.int(rect.Dy()),
+		unsafe.Pointer(pixelData(img)), &bmi, C.DIB_RGB_COLORS, C.SRCCOPY)
+}
+
+func (s *sysData) textareaMouseEvent(msg C.UINT, wparam C.WPARAM, lparam C.LPARAM) {
+	origin := areaScrollOrigin(s.textarea.hwndScroll)
+	pos := image.Pt(int(C.GET_X_LPARAM(lparam)), int(C.GET_Y_LPARAM(lparam))).Add(origin)
+
+	e := MouseEvent{
+		Pos:		pos,
+		Modifiers:	windowsModifiers(wparam),
+		Held:		windowsHeldButtons(wparam),
+	}
+
+	switch msg {
+	case C.WM_MOUSEMOVE:
+		// Down, Up both zero
+	case C.WM_LBUTTONDOWN, C.WM_MBUTTONDOWN, C.WM_RBUTTONDOWN, C.WM_XBUTTONDOWN:
+		e.Down = mapWindowsButton(msg, wparam)
+		e.Count = 1
+	case C.WM_LBUTTONDBLCLK, C.WM_MBUTTONDBLCLK, C.WM_RBUTTONDBLCLK, C.WM_XBUTTONDBLCLK:
+		e.Down = mapWindowsButton(msg, wparam)
+		e.Count = 2
+	case C.WM_LBUTTONUP, C.WM_MBUTTONUP, C.WM_RBUTTONUP, C.WM_XBUTTONUP:
+		e.Up = mapWindowsButton(msg, wparam)
+	}
+
+	repaint := s.handler.(TextAreaHandler).Mouse(e)
+	if repaint {
+		C.InvalidateRect(s.textarea.hwnd, nil, C.TRUE)
+	}
+}
+
+func (s *sysData) textareaWheelEvent(msg C.UINT, wparam C.WPARAM, lparam C.LPARAM) {
+	origin := areaScrollOrigin(s.textarea.hwndScroll)
+	pos := screenToClient(s.textarea.hwnd, int(C.GET_X_LPARAM(lparam)), int(C.GET_Y_LPARAM(lparam))).Add(origin)
+
+	notches := float64(C.GET_WHEEL_DELTA_WPARAM(wparam)) / float64(C.WHEEL_DELTA)
+	e := MouseEvent{
+		Pos:		pos,
+		Modifiers:	windowsModifiers(wparam),
+		Held:		windowsHeldButtons(wparam),
+	}
+	if msg == C.WM_MOUSEHWHEEL {
+		e.WheelX = notches
+	} else {
+		e.WheelY = notches
+	}
+
+	repaint := s.handler.(TextAreaHandler).Mouse(e)
+	if repaint {
+		C.InvalidateRect(s.textarea.hwnd, nil, C.TRUE)
+	}
+}
+
+// textareaChar handles WM_CHAR, which Windows only sends after TranslateMessage() has folded a WM_KEYDOWN (and any preceding dead-key or IME state) into an actual character; characters outside the BMP (most emoji, some CJK extension characters) arrive as a high surrogate followed by a low surrogate in two consecutive WM_CHARs, so the high surrogate is buffered in s.textarea.pendingHighSurrogate and combined with utf16.DecodeRune once its pair shows up, the same way cUnicharsToRunes (textarea_darwin.go) combines Cocoa's unichars.
+func (s *sysData) textareaChar(wparam C.WPARAM) {
+	c := uint16(wparam)
+
+	if c >= 0xD800 && c <= 0xDBFF { // high surrogate: stash it and wait for the low surrogate to follow
+		s.textarea.pendingHighSurrogate = c
+		return
+	}
+	if c >= 0xDC00 && c <= 0xDFFF { // low surrogate: combine with the stashed high surrogate
+		high := s.textarea.pendingHighSurrogate
+		s.textarea.pendingHighSurrogate = 0
+		r := utf16.DecodeRune(rune(high), rune(c))
+		if r == utf8.RuneError {
+			return // the high surrogate was missing or mismatched; drop the orphaned low surrogate
+		}
+		s.textareaDeliverChar(r)
+		return
+	}
+	s.textarea.pendingHighSurrogate = 0
+
+	r := rune(c)
+	if r < 0x20 && r != '\t' && r != '\n' && r != '\b' {
+		return // control characters (Ctrl+letter combinations, Escape, etc.) are not text
+	}
+	s.textareaDeliverChar(r)
+}
+
+func (s *sysData) textareaDeliverChar(r rune) {
+	repaint := s.handler.(TextAreaHandler).TextInput([]rune{r})
+	if repaint {
+		C.InvalidateRect(s.textarea.hwnd, nil, C.TRUE)
+	}
+}
+
+// textareaComposition handles WM_IME_COMPOSITION by pulling the in-progress composition string (GCS_COMPSTR) and cursor (GCS_CURSORPOS) out of the input context and forwarding them to CompositionUpdate.
+func (s *sysData) textareaComposition(lparam C.LPARAM) {
+	if C.DWORD(lparam)&C.GCS_COMPSTR == 0 {
+		return
+	}
+	himc := C.ImmGetContext(s.textarea.hwnd)
+	if himc == nil {
+		return
+	}
+	defer C.ImmReleaseContext(s.textarea.hwnd, himc)
+
+	n := C.ImmGetCompositionStringW(himc, C.GCS_COMPSTR, nil, 0)
+	if n < 0 {
+		return
+	}
+	buf := make([]uint16, n/2)
+	if n > 0 {
+		C.ImmGetCompositionStringW(himc, C.GCS_COMPSTR, unsafe.Pointer(&buf[0]), C.DWORD(n))
+	}
+	cursor := int(C.ImmGetCompositionStringW(himc, C.GCS_CURSORPOS, nil, 0))
+	s.handler.(TextAreaHandler).CompositionUpdate(string(utf16.Decode(buf)), cursor)
+}